@@ -0,0 +1,73 @@
+package main
+
+import "strings"
+
+// fuzzyMatch reports whether every rune of query appears in s, in
+// order, case-insensitively — the same loose matching used by fuzzy
+// file-finders, not a strict substring check.
+func fuzzyMatch(s, query string) bool {
+	if query == "" {
+		return true
+	}
+
+	s = strings.ToLower(s)
+	queryRunes := []rune(strings.ToLower(query))
+
+	qi := 0
+	for _, r := range s {
+		if qi >= len(queryRunes) {
+			break
+		}
+		if r == queryRunes[qi] {
+			qi++
+		}
+	}
+	return qi == len(queryRunes)
+}
+
+// matchesFilter reports whether a track matches the filter query across
+// artist, title, and album.
+func (t Track) matchesFilter(query string) bool {
+	if query == "" {
+		return true
+	}
+	return fuzzyMatch(t.Artist, query) || fuzzyMatch(t.Title, query) || fuzzyMatch(t.Album, query)
+}
+
+// matchesFilter reports whether a play history entry matches the filter
+// query across its track info and source.
+func (p PlayEntry) matchesFilter(query string) bool {
+	if query == "" {
+		return true
+	}
+	return fuzzyMatch(p.TrackInfo, query) || fuzzyMatch(p.Source, query)
+}
+
+// filteredTracks returns m.tracks narrowed by the active filter query.
+func (m model) filteredTracks() []Track {
+	if m.filterQuery == "" {
+		return m.tracks
+	}
+	var out []Track
+	for _, t := range m.tracks {
+		if t.matchesFilter(m.filterQuery) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// filteredPlayHistory returns m.playHistory narrowed by the active
+// filter query.
+func (m model) filteredPlayHistory() []PlayEntry {
+	if m.filterQuery == "" {
+		return m.playHistory
+	}
+	var out []PlayEntry
+	for _, p := range m.playHistory {
+		if p.matchesFilter(m.filterQuery) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// allLibrariesID is the sentinel library_id meaning "don't filter by
+// library" — the default when the browser opens against a database that
+// has never been split into separate collections.
+const allLibrariesID = 0
+
+// Library is a named, scannable collection of assets — e.g. separate
+// music/promo/jingle trees pointed at the same database.
+type Library struct {
+	ID         int
+	Name       string
+	Path       string
+	LastScanAt string
+}
+
+// ensureLibrarySchema creates the libraries table and adds library_id to
+// assets/play_history if this is the first run against a database that
+// predates multi-library support.
+func ensureLibrarySchema(db *sql.DB) error {
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS libraries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			path TEXT NOT NULL,
+			last_scan_at TEXT
+		)
+	`); err != nil {
+		return err
+	}
+
+	if err := addColumnIfMissing(db, "assets", "library_id", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "play_history", "library_id", "INTEGER"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// addColumnIfMissing adds column to table with the given SQLite type
+// unless it's already present. SQLite has no "ADD COLUMN IF NOT EXISTS",
+// so callers check PRAGMA table_info first.
+func addColumnIfMissing(db *sql.DB, table, column, sqlType string) error {
+	rows, err := db.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return err
+	}
+
+	exists := false
+	for rows.Next() {
+		var cid int
+		var name, colType string
+		var notNull, pk int
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		if name == column {
+			exists = true
+		}
+	}
+	rows.Close()
+
+	if exists {
+		return nil
+	}
+
+	_, err = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + sqlType)
+	return err
+}
+
+// loadLibraries returns all saved libraries, ordered by name.
+func loadLibraries(db *sql.DB) []Library {
+	rows, err := db.Query(`SELECT id, name, path, COALESCE(last_scan_at, '') FROM libraries ORDER BY name`)
+	if err != nil {
+		return []Library{}
+	}
+	defer rows.Close()
+
+	var libraries []Library
+	for rows.Next() {
+		var l Library
+		if err := rows.Scan(&l.ID, &l.Name, &l.Path, &l.LastScanAt); err != nil {
+			continue
+		}
+		libraries = append(libraries, l)
+	}
+
+	return libraries
+}
+
+// libraryFilterClause returns a "AND a.library_id = ?" fragment and its
+// bound argument, or an empty fragment and no argument when libraryID is
+// allLibrariesID.
+func libraryFilterClause(alias string, libraryID int) (string, []interface{}) {
+	if libraryID == allLibrariesID {
+		return "", nil
+	}
+	return " AND " + alias + ".library_id = ?", []interface{}{libraryID}
+}
+
+// saveLibrary inserts a new library row and backfills every still-
+// unscoped asset whose path falls under it, plus that asset's play
+// history, so registering a library on an existing collection makes
+// just its own files visible to per-library filtering rather than
+// claiming every orphaned asset regardless of where it lives. The
+// insert and both backfills run in one transaction so a failure partway
+// through can't leave the library registered with only some of its data
+// scoped to it.
+func saveLibrary(db *sql.DB, name, path string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`INSERT INTO libraries (name, path, last_scan_at) VALUES (?, ?, ?)`,
+		name, path, time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := backfillAssetLibrary(tx, id, path); err != nil {
+		return 0, err
+	}
+	if err := backfillPlayHistoryLibrary(tx, id); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// backfillAssetLibrary assigns libraryID to every still-unscoped asset
+// whose path falls under pathPrefix, so registering a library only
+// claims the files it actually owns — leaving assets under other
+// prefixes free to be claimed by libraries registered later.
+func backfillAssetLibrary(tx *sql.Tx, libraryID int64, pathPrefix string) error {
+	_, err := tx.Exec(
+		`UPDATE assets SET library_id = ? WHERE library_id IS NULL AND path LIKE ? ESCAPE '\'`,
+		libraryID, likePrefixPattern(pathPrefix),
+	)
+	return err
+}
+
+// likePrefixPattern turns pathPrefix into a LIKE pattern that matches
+// only paths under that directory, not merely starting with the same
+// characters (so "/music/rock" doesn't also match "/music/rocket-songs"),
+// with '%' and '_' in the prefix escaped so they match literally rather
+// than acting as LIKE wildcards.
+func likePrefixPattern(pathPrefix string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(pathPrefix)
+	if !strings.HasSuffix(escaped, "/") {
+		escaped += "/"
+	}
+	return escaped + "%"
+}
+
+// backfillPlayHistoryLibrary assigns libraryID to every still-unscoped
+// play_history row belonging to an asset that was just scoped to that
+// library (play_history has no path of its own to match against).
+func backfillPlayHistoryLibrary(tx *sql.Tx, libraryID int64) error {
+	_, err := tx.Exec(`
+		UPDATE play_history SET library_id = ?
+		WHERE library_id IS NULL
+		AND asset_id IN (SELECT id FROM assets WHERE library_id = ?)
+	`, libraryID, libraryID)
+	return err
+}
+
+// openLibraryForm resets the "Add Library" overlay to a blank name/path
+// pair.
+func (m *model) openLibraryForm() {
+	m.addingLibrary = true
+	m.libraryFormFocus = 0
+	m.libraryFormError = ""
+
+	m.libraryNameInput.SetValue("")
+	m.libraryNameInput.Focus()
+
+	m.libraryPathInput.SetValue("")
+	m.libraryPathInput.Blur()
+}
+
+// saveLibraryForm validates and persists the name/path overlay, returning
+// a user-facing error instead of registering a malformed library.
+func (m *model) saveLibraryForm() error {
+	name := strings.TrimSpace(m.libraryNameInput.Value())
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	path := strings.TrimSpace(m.libraryPathInput.Value())
+	if path == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if _, err := saveLibrary(m.db, name, path); err != nil {
+		return fmt.Errorf("save failed: %w", err)
+	}
+
+	return nil
+}
@@ -2,16 +2,18 @@ package main
 
 import (
 	"database/sql"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // Styles
@@ -64,6 +66,9 @@ const (
 	trackDetailView
 	statsView
 	playHistoryView
+	playlistsView
+	libraryPickerView
+	radioView
 )
 
 type sortColumn int
@@ -86,73 +91,136 @@ const (
 )
 
 type model struct {
-	db              *sql.DB
-	view            view
-	table           table.Model
-	historyTable    table.Model
-	viewport        viewport.Model
-	tracks          []Track
-	playHistory     []PlayEntry
-	stats           Stats
-	selected        int
-	width           int
-	height          int
-	ready           bool
-	sortCol         sortColumn
-	sortAsc         bool
-	historySortCol  historySortColumn
-	historySortAsc  bool
+	db                    *sql.DB
+	view                  view
+	table                 table.Model
+	historyTable          table.Model
+	playlistTable         table.Model
+	libraryTable          table.Model
+	radioTable            table.Model
+	viewport              viewport.Model
+	tracks                []Track
+	playHistory           []PlayEntry
+	playlists             []SmartPlaylist
+	selectedPlaylist      int
+	stats                 Stats
+	selected              int
+	width                 int
+	height                int
+	ready                 bool
+	sortCol               sortColumn
+	sortAsc               bool
+	historySortCol        historySortColumn
+	historySortAsc        bool
+	streamStatusURL       string
+	currentListeners      int
+	filtering             bool
+	filterQuery           string
+	filterInput           textinput.Model
+	tracksOffset          int
+	tracksExhausted       bool
+	loadingTracks         bool
+	historyOffset         int
+	historyExhausted      bool
+	loadingHistory        bool
+	libraries             []Library
+	selectedLibraryID     int
+	radioSeed             Track
+	radioTracks           []Track
+	exportMessage         string
+	editingPlaylist       bool
+	playlistFormFocus     int
+	playlistNameInput     textinput.Model
+	playlistCriteriaInput textarea.Model
+	playlistFormError     string
+	addingLibrary         bool
+	libraryFormFocus      int
+	libraryNameInput      textinput.Model
+	libraryPathInput      textinput.Model
+	libraryFormError      string
 }
 
 type Track struct {
-	ID           string
-	Path         string
-	Kind         string
-	DurationSec  float64
-	LoudnessLUFS float64
-	TruePeakDBTP float64
-	EnergyLevel  int
-	Title        string
-	Artist       string
-	Album        string
-	CreatedAt    string
-	PlayCount    int
-	LastPlayedAt string
+	ID            string
+	Path          string
+	Kind          string
+	DurationSec   float64
+	LoudnessLUFS  float64
+	TruePeakDBTP  float64
+	EnergyLevel   int
+	Title         string
+	Artist        string
+	Album         string
+	CreatedAt     string
+	PlayCount     int
+	LastPlayedAt  string
+	PeakListeners int
 }
 
 type PlayEntry struct {
-	ID        int
-	AssetID   string
-	PlayedAt  string
-	Source    string
-	TrackInfo string
+	ID            int
+	AssetID       string
+	PlayedAt      string
+	Source        string
+	TrackInfo     string
+	Listeners     int
+	PeakListeners int
 }
 
 type Stats struct {
-	TotalTracks   int
-	TotalPlays    int
-	TotalDuration float64
-	AvgEnergy     float64
-	Tracks24h     int
-	TopArtist     string
-	TopTrack      string
+	TotalTracks        int
+	TotalPlays         int
+	TotalDuration      float64
+	AvgEnergy          float64
+	Tracks24h          int
+	TopArtist          string
+	TopTrack           string
+	TopByPeakListeners string
+	PeakListeners      int
+	LibraryBreakdown   []LibraryStat
+}
+
+// LibraryStat is one row of the stats view's per-library breakdown.
+type LibraryStat struct {
+	Name       string
+	TrackCount int
+	PlayCount  int
 }
 
 func main() {
+	streamStatusURL := flag.String("stream-status-url", "", "Icecast/Shoutcast status-json.xsl URL to poll for live listener counts")
+	flag.Parse()
+
 	// Check if database exists
 	dbPath := "./db/radio.sqlite3"
-	if len(os.Args) > 1 {
-		dbPath = os.Args[1]
+	if args := flag.Args(); len(args) > 0 {
+		dbPath = args[0]
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open(sqliteDriverName, dbPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
 		os.Exit(1)
 	}
 	defer db.Close()
 
+	if err := ensurePlaylistSchema(db); err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing playlist schema: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := ensureStreamStatusSchema(db); err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing stream status schema: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := ensureLibrarySchema(db); err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing library schema: %v\n", err)
+		os.Exit(1)
+	}
+
 	m := initialModel(db)
+	m.streamStatusURL = *streamStatusURL
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -162,8 +230,8 @@ func main() {
 
 func initialModel(db *sql.DB) model {
 	// Load initial data with default sort
-	tracks := loadTracks(db, sortByArtist, true)
-	stats := loadStats(db)
+	tracks := loadTracks(db, sortByArtist, true, allLibrariesID)
+	stats := loadStats(db, allLibrariesID)
 
 	// Create table
 	columns := []table.Column{
@@ -214,6 +282,7 @@ func initialModel(db *sql.DB) model {
 		{Title: "Time", Width: 20},
 		{Title: "Source", Width: 10},
 		{Title: "Track", Width: 60},
+		{Title: "Peak Listeners", Width: 14},
 	}
 
 	ht := table.New(
@@ -224,23 +293,105 @@ func initialModel(db *sql.DB) model {
 	)
 	ht.SetStyles(s)
 
+	// Create playlist table
+	playlistColumns := []table.Column{
+		{Title: "Name", Width: 25},
+		{Title: "Rules", Width: 10},
+		{Title: "Order", Width: 12},
+		{Title: "Limit", Width: 6},
+		{Title: "Evaluated", Width: 15},
+	}
+
+	pt := table.New(
+		table.WithColumns(playlistColumns),
+		table.WithRows([]table.Row{}),
+		table.WithFocused(false),
+		table.WithHeight(20),
+	)
+	pt.SetStyles(s)
+
+	// Create library picker table
+	libraryColumns := []table.Column{
+		{Title: "Name", Width: 25},
+		{Title: "Path", Width: 40},
+		{Title: "Last Scan", Width: 15},
+	}
+
+	lt := table.New(
+		table.WithColumns(libraryColumns),
+		table.WithRows([]table.Row{}),
+		table.WithFocused(false),
+		table.WithHeight(20),
+	)
+	lt.SetStyles(s)
+
+	// Create radio recommendation table
+	radioColumns := []table.Column{
+		{Title: "Artist", Width: 20},
+		{Title: "Title", Width: 25},
+		{Title: "Album", Width: 20},
+		{Title: "Duration", Width: 8},
+		{Title: "Energy", Width: 6},
+	}
+
+	rt := table.New(
+		table.WithColumns(radioColumns),
+		table.WithRows([]table.Row{}),
+		table.WithFocused(false),
+		table.WithHeight(20),
+	)
+	rt.SetStyles(s)
+
+	fi := textinput.New()
+	fi.Placeholder = "filter..."
+	fi.Prompt = "/ "
+
+	pni := textinput.New()
+	pni.Placeholder = "playlist name"
+
+	pci := textarea.New()
+	pci.Placeholder = `{"rules":[{"field":"energy_level","operator":">","value":70}],"order_by":"random","limit":25}`
+	pci.SetWidth(70)
+	pci.SetHeight(8)
+
+	lni := textinput.New()
+	lni.Placeholder = "library name"
+
+	lpi := textinput.New()
+	lpi.Placeholder = "/path/to/music"
+
 	return model{
-		db:             db,
-		view:           trackListView,
-		table:          t,
-		historyTable:   ht,
-		tracks:         tracks,
-		stats:          stats,
-		selected:       0,
-		sortCol:        sortByArtist,
-		sortAsc:        true,
-		historySortCol: sortHistoryByTime,
-		historySortAsc: false, // Most recent first by default
+		db:                    db,
+		view:                  trackListView,
+		table:                 t,
+		historyTable:          ht,
+		playlistTable:         pt,
+		libraryTable:          lt,
+		radioTable:            rt,
+		filterInput:           fi,
+		playlistNameInput:     pni,
+		playlistCriteriaInput: pci,
+		libraryNameInput:      lni,
+		libraryPathInput:      lpi,
+		tracks:                tracks,
+		tracksOffset:          len(tracks),
+		tracksExhausted:       len(tracks) < tracksPageSize,
+		stats:                 stats,
+		selected:              0,
+		selectedPlaylist:      0,
+		sortCol:               sortByArtist,
+		sortAsc:               true,
+		historySortCol:        sortHistoryByTime,
+		historySortAsc:        false, // Most recent first by default
+		selectedLibraryID:     allLibrariesID,
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	if m.streamStatusURL == "" {
+		return nil
+	}
+	return tea.Batch(pollStreamStatusCmd(m.streamStatusURL), tickStreamStatusCmd())
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -253,11 +404,121 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.ready = true
 		return m, nil
 
+	case streamStatusMsg:
+		if msg.err == nil {
+			m.currentListeners = msg.listeners
+			recordStreamListeners(m.db, msg.listeners)
+		}
+		return m, nil
+
+	case streamStatusTickMsg:
+		return m, tea.Batch(pollStreamStatusCmd(m.streamStatusURL), tickStreamStatusCmd())
+
 	case tea.KeyMsg:
+		if m.addingLibrary {
+			switch msg.String() {
+			case "esc":
+				m.addingLibrary = false
+				m.libraryFormError = ""
+				return m, nil
+			case "tab":
+				if m.libraryFormFocus == 0 {
+					m.libraryFormFocus = 1
+					m.libraryNameInput.Blur()
+					m.libraryPathInput.Focus()
+				} else {
+					m.libraryFormFocus = 0
+					m.libraryPathInput.Blur()
+					m.libraryNameInput.Focus()
+				}
+				return m, nil
+			case "ctrl+s":
+				if err := m.saveLibraryForm(); err != nil {
+					m.libraryFormError = err.Error()
+					return m, nil
+				}
+				m.addingLibrary = false
+				m.libraryFormError = ""
+				m.libraries = loadLibraries(m.db)
+				m.updateLibraryTableRows()
+				return m, nil
+			}
+			if m.libraryFormFocus == 0 {
+				m.libraryNameInput, cmd = m.libraryNameInput.Update(msg)
+			} else {
+				m.libraryPathInput, cmd = m.libraryPathInput.Update(msg)
+			}
+			return m, cmd
+		}
+
+		if m.editingPlaylist {
+			switch msg.String() {
+			case "esc":
+				m.editingPlaylist = false
+				m.playlistFormError = ""
+				return m, nil
+			case "tab":
+				if m.playlistFormFocus == 0 {
+					m.playlistFormFocus = 1
+					m.playlistNameInput.Blur()
+					m.playlistCriteriaInput.Focus()
+				} else {
+					m.playlistFormFocus = 0
+					m.playlistCriteriaInput.Blur()
+					m.playlistNameInput.Focus()
+				}
+				return m, nil
+			case "ctrl+s":
+				if err := m.savePlaylistForm(); err != nil {
+					m.playlistFormError = err.Error()
+					return m, nil
+				}
+				m.editingPlaylist = false
+				m.playlistFormError = ""
+				m.playlists = loadSmartPlaylists(m.db)
+				m.updatePlaylistTableRows()
+				return m, nil
+			}
+			if m.playlistFormFocus == 0 {
+				m.playlistNameInput, cmd = m.playlistNameInput.Update(msg)
+			} else {
+				m.playlistCriteriaInput, cmd = m.playlistCriteriaInput.Update(msg)
+			}
+			return m, cmd
+		}
+
+		if m.filtering {
+			switch msg.String() {
+			case "esc":
+				m.filtering = false
+				m.filterQuery = ""
+				m.filterInput.Reset()
+				m.applyFilter()
+				return m, nil
+			case "enter":
+				m.filtering = false
+				m.filterQuery = m.filterInput.Value()
+				m.applyFilter()
+				return m, nil
+			}
+			m.filterInput, cmd = m.filterInput.Update(msg)
+			m.filterQuery = m.filterInput.Value()
+			m.applyFilter()
+			return m, cmd
+		}
+
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return m, tea.Quit
 
+		case "/":
+			if m.view == trackListView || m.view == playHistoryView {
+				m.filtering = true
+				m.filterInput.Focus()
+				return m, textinput.Blink
+			}
+			return m, nil
+
 		case "1":
 			m.view = trackListView
 			return m, nil
@@ -273,15 +534,107 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "4":
 			m.view = playHistoryView
-			m.playHistory = loadPlayHistory(m.db, 50, m.historySortCol, m.historySortAsc)
+			m.setPlayHistory(loadPlayHistory(m.db, m.historySortCol, m.historySortAsc, m.selectedLibraryID))
 			m.updateHistoryTableRows()
 			m.historyTable.Focus()
 			return m, nil
 
+		case "5":
+			m.view = playlistsView
+			m.playlists = loadSmartPlaylists(m.db)
+			m.updatePlaylistTableRows()
+			m.playlistTable.Focus()
+			return m, nil
+
+		case "n":
+			if m.view == playlistsView {
+				m.openPlaylistForm(SmartPlaylist{Criteria: PlaylistCriteria{OrderBy: "random", Limit: 25}})
+				return m, textinput.Blink
+			}
+			if m.view == libraryPickerView {
+				m.openLibraryForm()
+				return m, textinput.Blink
+			}
+			return m, nil
+
+		case "L":
+			m.view = libraryPickerView
+			m.libraries = loadLibraries(m.db)
+			m.updateLibraryTableRows()
+			m.libraryTable.Focus()
+			return m, nil
+
+		case "g":
+			if m.view == trackListView || m.view == trackDetailView {
+				tracks := m.filteredTracks()
+				idx := m.table.Cursor()
+				if m.view == trackDetailView {
+					idx = m.selected
+				}
+				if idx < len(tracks) {
+					m.radioSeed = tracks[idx]
+					pool := loadTracksWithFeatures(m.db, m.selectedLibraryID)
+					m.radioTracks = recommendTracks(pool, m.radioSeed)
+					m.exportMessage = ""
+					m.updateRadioTableRows()
+					m.view = radioView
+					m.radioTable.Focus()
+				}
+			}
+			return m, nil
+
+		case "e":
+			if m.view == radioView {
+				path := radioExportPath(m.radioSeed)
+				if err := exportM3U(m.radioTracks, path); err != nil {
+					m.exportMessage = fmt.Sprintf("export failed: %v", err)
+				} else {
+					m.exportMessage = fmt.Sprintf("exported to %s", path)
+				}
+			} else if m.view == playlistsView {
+				cursor := m.playlistTable.Cursor()
+				if cursor < len(m.playlists) {
+					m.openPlaylistForm(m.playlists[cursor])
+					return m, textinput.Blink
+				}
+			}
+			return m, nil
+
+		case "enter":
+			if m.view == playlistsView {
+				m.selectedPlaylist = m.playlistTable.Cursor()
+				if m.selectedPlaylist < len(m.playlists) {
+					p := m.playlists[m.selectedPlaylist]
+					if p.needsEvaluation() {
+						p.Tracks = evaluateSmartPlaylist(m.db, p)
+						m.playlists[m.selectedPlaylist] = p
+						m.updatePlaylistTableRows()
+					}
+					m.tracks = p.Tracks
+					m.tracksExhausted = true
+					m.view = trackListView
+					m.updateTableRows()
+				}
+			} else if m.view == libraryPickerView {
+				cursor := m.libraryTable.Cursor()
+				if cursor == 0 {
+					m.selectedLibraryID = allLibrariesID
+				} else if cursor-1 < len(m.libraries) {
+					m.selectedLibraryID = m.libraries[cursor-1].ID
+				}
+				m.view = trackListView
+				m.setTracks(loadTracks(m.db, m.sortCol, m.sortAsc, m.selectedLibraryID))
+				m.stats = loadStats(m.db, m.selectedLibraryID)
+				m.setPlayHistory(loadPlayHistory(m.db, m.historySortCol, m.historySortAsc, m.selectedLibraryID))
+				m.updateTableRows()
+				m.updateHistoryTableRows()
+			}
+			return m, nil
+
 		case "r":
 			// Refresh data
-			m.tracks = loadTracks(m.db, m.sortCol, m.sortAsc)
-			m.stats = loadStats(m.db)
+			m.setTracks(loadTracks(m.db, m.sortCol, m.sortAsc, m.selectedLibraryID))
+			m.stats = loadStats(m.db, m.selectedLibraryID)
 			m.updateTableRows()
 			return m, nil
 
@@ -289,11 +642,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Cycle through sort columns
 			if m.view == trackListView {
 				m.sortCol = (m.sortCol + 1) % 6
-				m.tracks = loadTracks(m.db, m.sortCol, m.sortAsc)
+				m.setTracks(loadTracks(m.db, m.sortCol, m.sortAsc, m.selectedLibraryID))
 				m.updateTableRows()
 			} else if m.view == playHistoryView {
 				m.historySortCol = (m.historySortCol + 1) % 3
-				m.playHistory = loadPlayHistory(m.db, 50, m.historySortCol, m.historySortAsc)
+				m.setPlayHistory(loadPlayHistory(m.db, m.historySortCol, m.historySortAsc, m.selectedLibraryID))
 				m.updateHistoryTableRows()
 			}
 			return m, nil
@@ -302,25 +655,59 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Reverse sort direction
 			if m.view == trackListView {
 				m.sortAsc = !m.sortAsc
-				m.tracks = loadTracks(m.db, m.sortCol, m.sortAsc)
+				m.setTracks(loadTracks(m.db, m.sortCol, m.sortAsc, m.selectedLibraryID))
 				m.updateTableRows()
 			} else if m.view == playHistoryView {
 				m.historySortAsc = !m.historySortAsc
-				m.playHistory = loadPlayHistory(m.db, 50, m.historySortCol, m.historySortAsc)
+				m.setPlayHistory(loadPlayHistory(m.db, m.historySortCol, m.historySortAsc, m.selectedLibraryID))
 				m.updateHistoryTableRows()
 			}
 			return m, nil
 		}
+
+	case tracksLoadedMsg:
+		m.loadingTracks = false
+		if msg.offset == m.tracksOffset {
+			m.tracks = append(m.tracks, msg.tracks...)
+			m.tracksOffset += len(msg.tracks)
+			if len(msg.tracks) < tracksPageSize {
+				m.tracksExhausted = true
+			}
+			m.applyFilter()
+		}
+		return m, nil
+
+	case historyLoadedMsg:
+		m.loadingHistory = false
+		if msg.offset == m.historyOffset {
+			m.playHistory = append(m.playHistory, msg.entries...)
+			m.historyOffset += len(msg.entries)
+			if len(msg.entries) < historyPageSize {
+				m.historyExhausted = true
+			}
+			m.applyFilter()
+		}
+		return m, nil
 	}
 
 	// Update the appropriate component based on view
 	switch m.view {
 	case trackListView:
 		m.table, cmd = m.table.Update(msg)
+		loadCmd := m.maybeLoadMoreTracks()
+		return m, tea.Batch(cmd, loadCmd)
 	case trackDetailView:
 		m.viewport, cmd = m.viewport.Update(msg)
 	case playHistoryView:
 		m.historyTable, cmd = m.historyTable.Update(msg)
+		loadCmd := m.maybeLoadMoreHistory()
+		return m, tea.Batch(cmd, loadCmd)
+	case playlistsView:
+		m.playlistTable, cmd = m.playlistTable.Update(msg)
+	case libraryPickerView:
+		m.libraryTable, cmd = m.libraryTable.Update(msg)
+	case radioView:
+		m.radioTable, cmd = m.radioTable.Update(msg)
 	}
 
 	return m, cmd
@@ -345,9 +732,20 @@ func (m model) View() string {
 		content = m.renderStats()
 	case playHistoryView:
 		content = m.renderPlayHistory()
+	case playlistsView:
+		content = m.renderPlaylists()
+	case libraryPickerView:
+		content = m.renderLibraryPicker()
+	case radioView:
+		content = m.renderRadio()
 	}
 
-	help := helpStyle.Render("[1] Tracks [2] Detail [3] Stats [4] History [Tab] Sort Column [Shift+Tab] Reverse [r] Refresh [q] Quit")
+	help := helpStyle.Render("[1] Tracks [2] Detail [3] Stats [4] History [5] Playlists [L] Library [g] Radio [/] Filter [Tab] Sort Column [Shift+Tab] Reverse [Enter] Load [r] Refresh [q] Quit")
+
+	footer := help
+	if (m.view == trackListView && m.loadingTracks) || (m.view == playHistoryView && m.loadingHistory) {
+		footer = lipgloss.JoinVertical(lipgloss.Left, helpStyle.Render("loading more..."), help)
+	}
 
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
@@ -355,7 +753,7 @@ func (m model) View() string {
 		"",
 		content,
 		"",
-		help,
+		footer,
 	)
 }
 
@@ -365,14 +763,52 @@ func (m model) renderTrackList() string {
 		sortIndicator = "â†“"
 	}
 	sortColName := []string{"Artist", "Title", "Album", "Duration", "Energy", "Plays"}[m.sortCol]
-	header := headerStyle.Render(fmt.Sprintf("Track Library (%d tracks) - Sort: %s %s",
-		len(m.tracks), sortColName, sortIndicator))
-	return lipgloss.JoinVertical(lipgloss.Left, header, m.table.View())
+	headerText := fmt.Sprintf("Track Library (%d/%d tracks) - Sort: %s %s",
+		len(m.filteredTracks()), m.stats.TotalTracks, sortColName, sortIndicator)
+	if m.filterQuery != "" {
+		headerText += fmt.Sprintf(" - Filter: '%s'", m.filterQuery)
+	}
+	header := headerStyle.Render(headerText)
+	content := lipgloss.JoinVertical(lipgloss.Left, header, m.table.View())
+	if m.filtering {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, m.filterInput.View())
+	}
+	return content
+}
+
+// setTracks replaces m.tracks with a freshly loaded first page and resets
+// paging state accordingly (used whenever the sort order changes or the
+// library is reloaded from scratch).
+func (m *model) setTracks(tracks []Track) {
+	m.tracks = tracks
+	m.tracksOffset = len(tracks)
+	m.tracksExhausted = len(tracks) < tracksPageSize
+	m.loadingTracks = false
+}
+
+// setPlayHistory replaces m.playHistory with a freshly loaded first page
+// and resets paging state accordingly.
+func (m *model) setPlayHistory(entries []PlayEntry) {
+	m.playHistory = entries
+	m.historyOffset = len(entries)
+	m.historyExhausted = len(entries) < historyPageSize
+	m.loadingHistory = false
+}
+
+// applyFilter recomputes the rows of whichever view is currently active
+// so the filter query takes effect immediately as the user types.
+func (m *model) applyFilter() {
+	switch m.view {
+	case trackListView:
+		m.updateTableRows()
+	case playHistoryView:
+		m.updateHistoryTableRows()
+	}
 }
 
 func (m *model) updateTableRows() {
 	rows := []table.Row{}
-	for _, t := range m.tracks {
+	for _, t := range m.filteredTracks() {
 		rows = append(rows, table.Row{
 			truncate(t.Artist, 20),
 			truncate(t.Title, 25),
@@ -387,11 +823,12 @@ func (m *model) updateTableRows() {
 }
 
 func (m model) renderTrackDetail() string {
-	if m.selected >= len(m.tracks) {
+	tracks := m.filteredTracks()
+	if m.selected >= len(tracks) {
 		return "No track selected"
 	}
 
-	t := m.tracks[m.selected]
+	t := tracks[m.selected]
 
 	details := fmt.Sprintf(`â•”â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•—
 â•‘  TRACK DETAILS                                                 â•‘
@@ -444,6 +881,7 @@ func (m model) renderStats() string {
 
 ðŸ†  Top Artist:         %s
 ðŸ¥‡  Most Played:        %s
+ðŸ“¡  Most-Listened:      %s (peak %d listeners)
 `,
 		s.TotalTracks,
 		s.TotalPlays,
@@ -452,8 +890,21 @@ func (m model) renderStats() string {
 		s.Tracks24h,
 		s.TopArtist,
 		s.TopTrack,
+		s.TopByPeakListeners,
+		s.PeakListeners,
 	)
 
+	if m.streamStatusURL != "" {
+		stats += fmt.Sprintf("\nðŸŽ§  Live Listeners:     %d\n", m.currentListeners)
+	}
+
+	if len(s.LibraryBreakdown) > 0 {
+		stats += "\nðŸ“‚  By Library:\n"
+		for _, b := range s.LibraryBreakdown {
+			stats += fmt.Sprintf("    %-20s %5d tracks, %5d plays\n", b.Name, b.TrackCount, b.PlayCount)
+		}
+	}
+
 	return statsStyle.Render(stats)
 }
 
@@ -463,26 +914,150 @@ func (m model) renderPlayHistory() string {
 		sortIndicator = "â†“"
 	}
 	sortColName := []string{"Time", "Source", "Track"}[m.historySortCol]
-	header := headerStyle.Render(fmt.Sprintf("Play History (%d plays) - Sort: %s %s",
-		len(m.playHistory), sortColName, sortIndicator))
-	return lipgloss.JoinVertical(lipgloss.Left, header, m.historyTable.View())
+	headerText := fmt.Sprintf("Play History (%d/%d plays) - Sort: %s %s",
+		len(m.filteredPlayHistory()), m.stats.TotalPlays, sortColName, sortIndicator)
+	if m.filterQuery != "" {
+		headerText += fmt.Sprintf(" - Filter: '%s'", m.filterQuery)
+	}
+	header := headerStyle.Render(headerText)
+	content := lipgloss.JoinVertical(lipgloss.Left, header, m.historyTable.View())
+	if m.filtering {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, m.filterInput.View())
+	}
+	return content
 }
 
 func (m *model) updateHistoryTableRows() {
 	rows := []table.Row{}
-	for _, p := range m.playHistory {
+	for _, p := range m.filteredPlayHistory() {
 		rows = append(rows, table.Row{
 			formatTimestamp(p.PlayedAt),
 			p.Source,
 			truncate(p.TrackInfo, 60),
+			fmt.Sprintf("%d", p.PeakListeners),
 		})
 	}
 	m.historyTable.SetRows(rows)
 }
 
+func (m model) renderPlaylists() string {
+	header := headerStyle.Render(fmt.Sprintf("Smart Playlists (%d saved) - [n] New [e] Edit [Enter] Load", len(m.playlists)))
+	content := lipgloss.JoinVertical(lipgloss.Left, header, m.playlistTable.View())
+	if m.editingPlaylist {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, m.renderPlaylistForm())
+	}
+	return content
+}
+
+func (m model) renderPlaylistForm() string {
+	form := lipgloss.JoinVertical(lipgloss.Left,
+		"Name: "+m.playlistNameInput.View(),
+		"Criteria (JSON):",
+		m.playlistCriteriaInput.View(),
+		helpStyle.Render("[Tab] Switch Field  [Ctrl+S] Save  [Esc] Cancel"),
+	)
+	if m.playlistFormError != "" {
+		form = lipgloss.JoinVertical(lipgloss.Left, form, helpStyle.Render("Error: "+m.playlistFormError))
+	}
+	return detailStyle.Render(form)
+}
+
+func (m *model) updatePlaylistTableRows() {
+	rows := []table.Row{}
+	for _, p := range m.playlists {
+		evaluated := "never"
+		if p.EvaluatedAt != "" {
+			evaluated = formatRelativeTime(p.EvaluatedAt)
+		}
+		rows = append(rows, table.Row{
+			truncate(p.Name, 25),
+			fmt.Sprintf("%d", len(p.Criteria.Rules)),
+			p.Criteria.OrderBy,
+			fmt.Sprintf("%d", p.Criteria.Limit),
+			evaluated,
+		})
+	}
+	m.playlistTable.SetRows(rows)
+	// bubbles/table leaves the cursor at -1 once SetRows sees an empty
+	// slice and never recovers it on its own — re-clamp so a save that
+	// takes the table from 0 to 1+ rows doesn't leave Cursor() at -1.
+	m.playlistTable.SetCursor(m.playlistTable.Cursor())
+}
+
+func (m model) renderLibraryPicker() string {
+	header := headerStyle.Render(fmt.Sprintf("Libraries (%d) - [n] Add [Enter] to select", len(m.libraries)))
+	content := lipgloss.JoinVertical(lipgloss.Left, header, m.libraryTable.View())
+	if m.addingLibrary {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, m.renderLibraryForm())
+	}
+	return content
+}
+
+func (m model) renderLibraryForm() string {
+	form := lipgloss.JoinVertical(lipgloss.Left,
+		"Name: "+m.libraryNameInput.View(),
+		"Path: "+m.libraryPathInput.View(),
+		helpStyle.Render("[Tab] Switch Field  [Ctrl+S] Save  [Esc] Cancel"),
+	)
+	if m.libraryFormError != "" {
+		form = lipgloss.JoinVertical(lipgloss.Left, form, helpStyle.Render("Error: "+m.libraryFormError))
+	}
+	return detailStyle.Render(form)
+}
+
+// updateLibraryTableRows rebuilds the picker's rows, always leading with
+// an "All Libraries" entry at cursor 0 so clearing the scope back to
+// allLibrariesID doesn't require a separate keybinding.
+func (m *model) updateLibraryTableRows() {
+	rows := []table.Row{
+		{"All Libraries", "", ""},
+	}
+	for _, l := range m.libraries {
+		rows = append(rows, table.Row{
+			truncate(l.Name, 25),
+			truncate(l.Path, 40),
+			formatRelativeTime(l.LastScanAt),
+		})
+	}
+	m.libraryTable.SetRows(rows)
+}
+
+func (m model) renderRadio() string {
+	headerText := fmt.Sprintf("Radio seeded from \"%s - %s\" (%d tracks)",
+		m.radioSeed.Artist, m.radioSeed.Title, len(m.radioTracks))
+	header := headerStyle.Render(headerText)
+	content := lipgloss.JoinVertical(lipgloss.Left, header, m.radioTable.View())
+	if m.exportMessage != "" {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, helpStyle.Render(m.exportMessage))
+	}
+	return content
+}
+
+func (m *model) updateRadioTableRows() {
+	rows := []table.Row{}
+	for _, t := range m.radioTracks {
+		rows = append(rows, table.Row{
+			truncate(t.Artist, 20),
+			truncate(t.Title, 25),
+			truncate(t.Album, 20),
+			formatDuration(t.DurationSec),
+			fmt.Sprintf("%d", t.EnergyLevel),
+		})
+	}
+	m.radioTable.SetRows(rows)
+}
+
 // Database queries
 
-func loadTracks(db *sql.DB, sortCol sortColumn, ascending bool) []Track {
+// loadTracks loads the first page of the track library in the given
+// sort order. Use loadTracksPage directly to fetch subsequent pages.
+func loadTracks(db *sql.DB, sortCol sortColumn, ascending bool, libraryID int) []Track {
+	return loadTracksPage(db, sortCol, ascending, tracksPageSize, 0, libraryID)
+}
+
+// loadTracksPage loads up to limit tracks starting at offset in the
+// given sort order, scoped to libraryID (allLibrariesID for no filter).
+func loadTracksPage(db *sql.DB, sortCol sortColumn, ascending bool, limit, offset, libraryID int) []Track {
 	orderBy := "a.artist, a.title"
 	switch sortCol {
 	case sortByArtist:
@@ -503,6 +1078,8 @@ func loadTracks(db *sql.DB, sortCol sortColumn, ascending bool) []Track {
 		orderBy += " DESC"
 	}
 
+	libraryWhere, libraryArgs := libraryFilterClause("a", libraryID)
+
 	query := fmt.Sprintf(`
 		SELECT
 			a.id, a.path, a.kind, a.duration_sec,
@@ -513,15 +1090,18 @@ func loadTracks(db *sql.DB, sortCol sortColumn, ascending bool) []Track {
 			COALESCE(a.album, 'Unknown'),
 			a.created_at,
 			COUNT(p.id) as play_count,
-			MAX(p.played_at) as last_played_at
+			MAX(p.played_at) as last_played_at,
+			COALESCE(MAX(p.peak_listeners), 0) as peak_listeners
 		FROM assets a
 		LEFT JOIN play_history p ON a.id = p.asset_id
-		WHERE a.kind = 'music'
+		WHERE a.kind = 'music' %s
 		GROUP BY a.id
 		ORDER BY %s
-	`, orderBy)
+		LIMIT ? OFFSET ?
+	`, libraryWhere, orderBy)
 
-	rows, err := db.Query(query)
+	args := append(libraryArgs, limit, offset)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return []Track{}
 	}
@@ -535,7 +1115,7 @@ func loadTracks(db *sql.DB, sortCol sortColumn, ascending bool) []Track {
 			&t.ID, &t.Path, &t.Kind, &t.DurationSec,
 			&t.LoudnessLUFS, &t.TruePeakDBTP, &t.EnergyLevel,
 			&t.Title, &t.Artist, &t.Album, &t.CreatedAt, &t.PlayCount,
-			&lastPlayed,
+			&lastPlayed, &t.PeakListeners,
 		)
 		if err != nil {
 			continue
@@ -549,54 +1129,97 @@ func loadTracks(db *sql.DB, sortCol sortColumn, ascending bool) []Track {
 	return tracks
 }
 
-func loadStats(db *sql.DB) Stats {
+func loadStats(db *sql.DB, libraryID int) Stats {
 	var s Stats
 
+	assetsWhere, assetsArgs := libraryFilterClause("assets", libraryID)
+	playsWhere, playsArgs := libraryFilterClause("play_history", libraryID)
+	aWhere, aArgs := libraryFilterClause("a", libraryID)
+
 	// Total tracks
-	db.QueryRow("SELECT COUNT(*) FROM assets WHERE kind = 'music'").Scan(&s.TotalTracks)
+	db.QueryRow("SELECT COUNT(*) FROM assets WHERE kind = 'music'"+assetsWhere, assetsArgs...).Scan(&s.TotalTracks)
 
 	// Total plays
-	db.QueryRow("SELECT COUNT(*) FROM play_history WHERE source = 'music'").Scan(&s.TotalPlays)
+	db.QueryRow("SELECT COUNT(*) FROM play_history WHERE source = 'music'"+playsWhere, playsArgs...).Scan(&s.TotalPlays)
 
 	// Total duration
-	db.QueryRow("SELECT COALESCE(SUM(duration_sec), 0) FROM assets WHERE kind = 'music'").Scan(&s.TotalDuration)
+	db.QueryRow("SELECT COALESCE(SUM(duration_sec), 0) FROM assets WHERE kind = 'music'"+assetsWhere, assetsArgs...).Scan(&s.TotalDuration)
 
 	// Average energy
-	db.QueryRow("SELECT COALESCE(AVG(energy_level), 0) FROM assets WHERE kind = 'music' AND energy_level IS NOT NULL").Scan(&s.AvgEnergy)
+	db.QueryRow("SELECT COALESCE(AVG(energy_level), 0) FROM assets WHERE kind = 'music' AND energy_level IS NOT NULL"+assetsWhere, assetsArgs...).Scan(&s.AvgEnergy)
 
 	// Plays in last 24h
 	db.QueryRow(`
 		SELECT COUNT(*) FROM play_history
 		WHERE source = 'music'
 		AND datetime(played_at) > datetime('now', '-24 hours')
-	`).Scan(&s.Tracks24h)
+	`+playsWhere, playsArgs...).Scan(&s.Tracks24h)
 
 	// Top artist
-	db.QueryRow(`
+	db.QueryRow(fmt.Sprintf(`
 		SELECT COALESCE(a.artist, 'Unknown')
 		FROM assets a
 		JOIN play_history p ON a.id = p.asset_id
-		WHERE p.source = 'music'
+		WHERE p.source = 'music' %s
 		GROUP BY a.artist
 		ORDER BY COUNT(*) DESC
 		LIMIT 1
-	`).Scan(&s.TopArtist)
+	`, aWhere), aArgs...).Scan(&s.TopArtist)
 
 	// Most played track
-	db.QueryRow(`
+	db.QueryRow(fmt.Sprintf(`
 		SELECT COALESCE(a.title || ' - ' || a.artist, 'Unknown')
 		FROM assets a
 		JOIN play_history p ON a.id = p.asset_id
-		WHERE p.source = 'music'
+		WHERE p.source = 'music' %s
 		GROUP BY a.id
 		ORDER BY COUNT(*) DESC
 		LIMIT 1
-	`).Scan(&s.TopTrack)
+	`, aWhere), aArgs...).Scan(&s.TopTrack)
+
+	// Most-listened track by peak listeners
+	db.QueryRow(fmt.Sprintf(`
+		SELECT COALESCE(a.title || ' - ' || a.artist, 'Unknown'), COALESCE(MAX(p.peak_listeners), 0)
+		FROM assets a
+		JOIN play_history p ON a.id = p.asset_id
+		WHERE p.source = 'music' %s
+		GROUP BY a.id
+		ORDER BY MAX(p.peak_listeners) DESC
+		LIMIT 1
+	`, aWhere), aArgs...).Scan(&s.TopByPeakListeners, &s.PeakListeners)
+
+	// Per-library breakdown
+	rows, err := db.Query(`
+		SELECT l.name, COUNT(DISTINCT a.id), COUNT(p.id)
+		FROM libraries l
+		LEFT JOIN assets a ON a.library_id = l.id AND a.kind = 'music'
+		LEFT JOIN play_history p ON p.library_id = l.id AND p.source = 'music'
+		GROUP BY l.id
+		ORDER BY l.name
+	`)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var b LibraryStat
+			if err := rows.Scan(&b.Name, &b.TrackCount, &b.PlayCount); err != nil {
+				continue
+			}
+			s.LibraryBreakdown = append(s.LibraryBreakdown, b)
+		}
+	}
 
 	return s
 }
 
-func loadPlayHistory(db *sql.DB, limit int, sortCol historySortColumn, ascending bool) []PlayEntry {
+// loadPlayHistory loads the first page of play history in the given
+// sort order. Use loadPlayHistoryPage directly to fetch subsequent pages.
+func loadPlayHistory(db *sql.DB, sortCol historySortColumn, ascending bool, libraryID int) []PlayEntry {
+	return loadPlayHistoryPage(db, historyPageSize, 0, sortCol, ascending, libraryID)
+}
+
+// loadPlayHistoryPage loads up to limit play history entries starting
+// at offset in the given sort order, scoped to libraryID.
+func loadPlayHistoryPage(db *sql.DB, limit, offset int, sortCol historySortColumn, ascending bool, libraryID int) []PlayEntry {
 	orderBy := "p.played_at DESC"
 	switch sortCol {
 	case sortHistoryByTime:
@@ -615,17 +1238,22 @@ func loadPlayHistory(db *sql.DB, limit int, sortCol historySortColumn, ascending
 		}
 	}
 
+	libraryWhere, libraryArgs := libraryFilterClause("p", libraryID)
+
 	query := fmt.Sprintf(`
 		SELECT
 			p.id, p.asset_id, p.played_at, p.source,
-			COALESCE(a.title || ' - ' || a.artist, p.asset_id) as track_info
+			COALESCE(a.title || ' - ' || a.artist, p.asset_id) as track_info,
+			COALESCE(p.listeners, 0), COALESCE(p.peak_listeners, 0)
 		FROM play_history p
 		LEFT JOIN assets a ON p.asset_id = a.id
+		WHERE 1=1 %s
 		ORDER BY %s
-		LIMIT ?
-	`, orderBy)
+		LIMIT ? OFFSET ?
+	`, libraryWhere, orderBy)
 
-	rows, err := db.Query(query, limit)
+	args := append(libraryArgs, limit, offset)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return []PlayEntry{}
 	}
@@ -634,7 +1262,7 @@ func loadPlayHistory(db *sql.DB, limit int, sortCol historySortColumn, ascending
 	var entries []PlayEntry
 	for rows.Next() {
 		var e PlayEntry
-		err := rows.Scan(&e.ID, &e.AssetID, &e.PlayedAt, &e.Source, &e.TrackInfo)
+		err := rows.Scan(&e.ID, &e.AssetID, &e.PlayedAt, &e.Source, &e.TrackInfo, &e.Listeners, &e.PeakListeners)
 		if err != nil {
 			continue
 		}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"database/sql"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tracksPageSize and historyPageSize bound how many rows a single query
+// pulls at a time. Libraries with tens of thousands of tracks stall the
+// UI if loadTracks reads everything up front, so the table loads in
+// windows and fetches the next one as the cursor approaches the end.
+const (
+	tracksPageSize  = 200
+	historyPageSize = 200
+
+	// loadMoreThreshold is how close to the end of the currently loaded
+	// rows the cursor must get before the next page is requested.
+	loadMoreThreshold = 20
+)
+
+// tracksLoadedMsg carries a page of tracks back into Update. Offset
+// echoes the request so a stale in-flight page (e.g. after a sort
+// change resets tracksOffset) can be discarded instead of appended.
+type tracksLoadedMsg struct {
+	tracks []Track
+	offset int
+}
+
+// historyLoadedMsg carries a page of play history back into Update.
+type historyLoadedMsg struct {
+	entries []PlayEntry
+	offset  int
+}
+
+// loadMoreTracksCmd fetches the next page of tracks starting at offset
+// without blocking Update.
+func loadMoreTracksCmd(db *sql.DB, sortCol sortColumn, ascending bool, offset, libraryID int) tea.Cmd {
+	return func() tea.Msg {
+		tracks := loadTracksPage(db, sortCol, ascending, tracksPageSize, offset, libraryID)
+		return tracksLoadedMsg{tracks: tracks, offset: offset}
+	}
+}
+
+// loadMoreHistoryCmd fetches the next page of play history starting at
+// offset without blocking Update.
+func loadMoreHistoryCmd(db *sql.DB, sortCol historySortColumn, ascending bool, offset, libraryID int) tea.Cmd {
+	return func() tea.Msg {
+		entries := loadPlayHistoryPage(db, historyPageSize, offset, sortCol, ascending, libraryID)
+		return historyLoadedMsg{entries: entries, offset: offset}
+	}
+}
+
+// maybeLoadMoreTracks returns a Cmd to fetch the next page of tracks if
+// the table cursor has scrolled near the end of what's loaded, nil
+// otherwise.
+func (m *model) maybeLoadMoreTracks() tea.Cmd {
+	if m.loadingTracks || m.tracksExhausted {
+		return nil
+	}
+	if m.table.Cursor() < len(m.table.Rows())-loadMoreThreshold {
+		return nil
+	}
+
+	m.loadingTracks = true
+	return loadMoreTracksCmd(m.db, m.sortCol, m.sortAsc, m.tracksOffset, m.selectedLibraryID)
+}
+
+// maybeLoadMoreHistory returns a Cmd to fetch the next page of play
+// history if the table cursor has scrolled near the end of what's
+// loaded, nil otherwise.
+func (m *model) maybeLoadMoreHistory() tea.Cmd {
+	if m.loadingHistory || m.historyExhausted {
+		return nil
+	}
+	if m.historyTable.Cursor() < len(m.historyTable.Rows())-loadMoreThreshold {
+		return nil
+	}
+
+	m.loadingHistory = true
+	return loadMoreHistoryCmd(m.db, m.historySortCol, m.historySortAsc, m.historyOffset, m.selectedLibraryID)
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriverName is registered with a REGEXP function so the "regex"
+// playlist operator works — go-sqlite3 doesn't implement REGEXP itself,
+// it just parses "X REGEXP Y" into a call to a user function named
+// "regexp" that must be registered on connect.
+const sqliteDriverName = "sqlite3_with_regexp"
+
+func init() {
+	sql.Register(sqliteDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("regexp", func(pattern, value string) (bool, error) {
+				return regexp.MatchString(pattern, value)
+			}, true)
+		},
+	})
+}
+
+// PlaylistRule is a single predicate in a smart playlist's criteria.
+// Field/Operator pairs are compiled to a parameterized SQL fragment by
+// toSQL; Value is interpreted according to Field's type (numeric fields
+// compare as float64, string fields as text, temporal fields as an
+// "in the last N days" window).
+type PlaylistRule struct {
+	Field    string      `json:"field"`
+	Operator string      `json:"operator"`
+	Value    interface{} `json:"value"`
+}
+
+// PlaylistCriteria is the JSON-serialized rule set stored in the
+// smart_playlists table: a flat AND of rules plus ordering/limit knobs.
+type PlaylistCriteria struct {
+	Rules   []PlaylistRule `json:"rules"`
+	OrderBy string         `json:"order_by"` // "random", "artist", "title", "energy_level", "last_played"
+	Limit   int            `json:"limit"`
+}
+
+// SmartPlaylist is a saved rule set, lazily evaluated against assets/
+// play_history and cached until EvaluatedAt is older than playlistTTL.
+type SmartPlaylist struct {
+	ID          int
+	Name        string
+	Criteria    PlaylistCriteria
+	EvaluatedAt string
+	Tracks      []Track
+}
+
+// playlistTTL bounds how long a cached evaluation is reused before a
+// Tab/select on the Playlists view re-runs the compiled query.
+const playlistTTL = 5 * time.Minute
+
+var numericPlaylistFields = map[string]string{
+	"loudness_lufs":  "a.loudness_lufs",
+	"true_peak_dbtp": "a.true_peak_dbtp",
+	"energy_level":   "a.energy_level",
+	"duration_sec":   "a.duration_sec",
+	"play_count":     "play_count",
+}
+
+var stringPlaylistFields = map[string]string{
+	"artist": "a.artist",
+	"title":  "a.title",
+	"album":  "a.album",
+}
+
+var temporalPlaylistFields = map[string]string{
+	"last_played": "last_played_at",
+	"created_at":  "a.created_at",
+}
+
+// toSQL compiles a single rule to a "column op ?" fragment and its bound
+// argument. Unknown fields/operators compile to "1=0" so a bad rule
+// (a typo'd field, a future field rename) never silently widens the
+// result set to "match everything" — it excludes everything instead,
+// which is at least visibly wrong rather than quietly wrong.
+func (r PlaylistRule) toSQL() (string, interface{}) {
+	if col, ok := numericPlaylistFields[r.Field]; ok {
+		switch r.Operator {
+		case ">":
+			return col + " > ?", r.Value
+		case ">=":
+			return col + " >= ?", r.Value
+		case "<":
+			return col + " < ?", r.Value
+		case "<=":
+			return col + " <= ?", r.Value
+		case "=":
+			return col + " = ?", r.Value
+		case "!=":
+			return col + " != ?", r.Value
+		}
+	}
+
+	if col, ok := stringPlaylistFields[r.Field]; ok {
+		val, _ := r.Value.(string)
+		switch r.Operator {
+		case "equals":
+			return col + " = ?", val
+		case "!=":
+			return col + " != ?", val
+		case "contains":
+			return col + " LIKE ?", "%" + val + "%"
+		case "regex":
+			return col + " REGEXP ?", val
+		}
+	}
+
+	if col, ok := temporalPlaylistFields[r.Field]; ok {
+		days, _ := r.Value.(float64)
+		switch r.Operator {
+		case "in_last_days":
+			return fmt.Sprintf("datetime(%s) > datetime('now', ?)", col), fmt.Sprintf("-%d days", int(days))
+		case "not_in_last_days":
+			return fmt.Sprintf("(%s IS NULL OR datetime(%s) <= datetime('now', ?))", col, col), fmt.Sprintf("-%d days", int(days))
+		}
+	}
+
+	return "1=0", nil
+}
+
+// toSQL compiles the full criteria to a WHERE clause (sans "WHERE"), its
+// bound args in order, and an ORDER BY fragment.
+func (c PlaylistCriteria) toSQL() (string, []interface{}, string) {
+	clauses := []string{}
+	args := []interface{}{}
+
+	for _, r := range c.Rules {
+		clause, arg := r.toSQL()
+		clauses = append(clauses, clause)
+		if arg != nil {
+			args = append(args, arg)
+		}
+	}
+
+	where := "1=1"
+	if len(clauses) > 0 {
+		where = strings.Join(clauses, " AND ")
+	}
+
+	orderBy := "RANDOM()"
+	switch c.OrderBy {
+	case "artist":
+		orderBy = "a.artist"
+	case "title":
+		orderBy = "a.title"
+	case "energy_level":
+		orderBy = "a.energy_level DESC"
+	case "last_played":
+		orderBy = "last_played_at DESC"
+	}
+
+	return where, args, orderBy
+}
+
+// ensurePlaylistSchema creates the smart_playlists table if this is the
+// first run against a database predating the playlist subsystem. The
+// unique index on name is required by saveSmartPlaylist's
+// ON CONFLICT(name) upsert.
+func ensurePlaylistSchema(db *sql.DB) error {
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS smart_playlists (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			criteria TEXT NOT NULL,
+			evaluated_at TEXT
+		)
+	`); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_smart_playlists_name ON smart_playlists (name)`)
+	return err
+}
+
+// loadSmartPlaylists reads all saved rule sets without resolving tracks.
+func loadSmartPlaylists(db *sql.DB) []SmartPlaylist {
+	rows, err := db.Query(`SELECT id, name, criteria, COALESCE(evaluated_at, '') FROM smart_playlists ORDER BY name`)
+	if err != nil {
+		return []SmartPlaylist{}
+	}
+	defer rows.Close()
+
+	var playlists []SmartPlaylist
+	for rows.Next() {
+		var p SmartPlaylist
+		var criteriaJSON string
+		if err := rows.Scan(&p.ID, &p.Name, &criteriaJSON, &p.EvaluatedAt); err != nil {
+			continue
+		}
+		if err := json.Unmarshal([]byte(criteriaJSON), &p.Criteria); err != nil {
+			continue
+		}
+		playlists = append(playlists, p)
+	}
+
+	return playlists
+}
+
+// saveSmartPlaylist inserts or updates a saved rule set by name.
+func saveSmartPlaylist(db *sql.DB, name string, criteria PlaylistCriteria) error {
+	criteriaJSON, err := json.Marshal(criteria)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO smart_playlists (name, criteria, evaluated_at)
+		VALUES (?, ?, NULL)
+		ON CONFLICT(name) DO UPDATE SET criteria = excluded.criteria, evaluated_at = NULL
+	`, name, string(criteriaJSON))
+	return err
+}
+
+// openPlaylistForm resets the create/edit overlay, pre-filling it with
+// p's name and criteria — pass a zero-value SmartPlaylist to start a new
+// one blank.
+func (m *model) openPlaylistForm(p SmartPlaylist) {
+	m.editingPlaylist = true
+	m.playlistFormFocus = 0
+	m.playlistFormError = ""
+
+	m.playlistNameInput.SetValue(p.Name)
+	m.playlistNameInput.Focus()
+
+	criteriaJSON, _ := json.MarshalIndent(p.Criteria, "", "  ")
+	m.playlistCriteriaInput.SetValue(string(criteriaJSON))
+	m.playlistCriteriaInput.Blur()
+}
+
+// savePlaylistForm validates and persists the name/criteria-JSON overlay,
+// returning a user-facing error instead of saving malformed input.
+func (m *model) savePlaylistForm() error {
+	name := strings.TrimSpace(m.playlistNameInput.Value())
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	var criteria PlaylistCriteria
+	if err := json.Unmarshal([]byte(m.playlistCriteriaInput.Value()), &criteria); err != nil {
+		return fmt.Errorf("invalid criteria JSON: %w", err)
+	}
+
+	if err := saveSmartPlaylist(m.db, name, criteria); err != nil {
+		return fmt.Errorf("save failed: %w", err)
+	}
+
+	return nil
+}
+
+// needsEvaluation reports whether a playlist's cached track list is
+// stale and should be re-queried rather than reused as-is.
+func (p SmartPlaylist) needsEvaluation() bool {
+	if p.EvaluatedAt == "" {
+		return true
+	}
+	evaluated, err := time.Parse(time.RFC3339, p.EvaluatedAt)
+	if err != nil {
+		return true
+	}
+	return time.Since(evaluated) > playlistTTL
+}
+
+// evaluateSmartPlaylist compiles the playlist's criteria to SQL, runs it
+// against assets/play_history, and stamps EvaluatedAt in the database so
+// the next access within playlistTTL can skip the query.
+func evaluateSmartPlaylist(db *sql.DB, p SmartPlaylist) []Track {
+	where, args, orderBy := p.Criteria.toSQL()
+	limit := p.Criteria.Limit
+	if limit <= 0 {
+		limit = 25
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			a.id, a.path, a.kind, a.duration_sec,
+			COALESCE(a.loudness_lufs, 0), COALESCE(a.true_peak_dbtp, 0),
+			COALESCE(a.energy_level, 0),
+			COALESCE(a.title, 'Unknown'),
+			COALESCE(a.artist, 'Unknown'),
+			COALESCE(a.album, 'Unknown'),
+			a.created_at,
+			COUNT(p.id) as play_count,
+			MAX(p.played_at) as last_played_at
+		FROM assets a
+		LEFT JOIN play_history p ON a.id = p.asset_id
+		WHERE a.kind = 'music' AND (%s)
+		GROUP BY a.id
+		ORDER BY %s
+		LIMIT ?
+	`, where, orderBy)
+
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return []Track{}
+	}
+	defer rows.Close()
+
+	var tracks []Track
+	for rows.Next() {
+		var t Track
+		var lastPlayed sql.NullString
+		err := rows.Scan(
+			&t.ID, &t.Path, &t.Kind, &t.DurationSec,
+			&t.LoudnessLUFS, &t.TruePeakDBTP, &t.EnergyLevel,
+			&t.Title, &t.Artist, &t.Album, &t.CreatedAt, &t.PlayCount,
+			&lastPlayed,
+		)
+		if err != nil {
+			continue
+		}
+		if lastPlayed.Valid {
+			t.LastPlayedAt = lastPlayed.String
+		}
+		tracks = append(tracks, t)
+	}
+
+	db.Exec(`UPDATE smart_playlists SET evaluated_at = ? WHERE id = ?`, time.Now().UTC().Format(time.RFC3339), p.ID)
+
+	return tracks
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// loadTracksWithFeatures loads every music track scoped to libraryID with
+// the audio features recommendTracks ranks on. Recommendations rank
+// against the whole pool, not just whatever page loadTracksPage last
+// fetched, so this bypasses pagination entirely.
+func loadTracksWithFeatures(db *sql.DB, libraryID int) []Track {
+	libraryWhere, libraryArgs := libraryFilterClause("a", libraryID)
+
+	query := fmt.Sprintf(`
+		SELECT
+			a.id, a.path, a.kind, a.duration_sec,
+			COALESCE(a.loudness_lufs, 0), COALESCE(a.true_peak_dbtp, 0),
+			COALESCE(a.energy_level, 0),
+			COALESCE(a.title, 'Unknown'),
+			COALESCE(a.artist, 'Unknown'),
+			COALESCE(a.album, 'Unknown'),
+			a.created_at,
+			COUNT(p.id) as play_count,
+			MAX(p.played_at) as last_played_at,
+			COALESCE(MAX(p.peak_listeners), 0) as peak_listeners
+		FROM assets a
+		LEFT JOIN play_history p ON a.id = p.asset_id
+		WHERE a.kind = 'music' %s
+		GROUP BY a.id
+	`, libraryWhere)
+
+	rows, err := db.Query(query, libraryArgs...)
+	if err != nil {
+		return []Track{}
+	}
+	defer rows.Close()
+
+	var tracks []Track
+	for rows.Next() {
+		var t Track
+		var lastPlayed sql.NullString
+		err := rows.Scan(
+			&t.ID, &t.Path, &t.Kind, &t.DurationSec,
+			&t.LoudnessLUFS, &t.TruePeakDBTP, &t.EnergyLevel,
+			&t.Title, &t.Artist, &t.Album, &t.CreatedAt, &t.PlayCount,
+			&lastPlayed, &t.PeakListeners,
+		)
+		if err != nil {
+			continue
+		}
+		if lastPlayed.Valid {
+			t.LastPlayedAt = lastPlayed.String
+		}
+		tracks = append(tracks, t)
+	}
+
+	return tracks
+}
+
+// radioPlaylistSize caps how many tracks recommendTracks returns.
+const radioPlaylistSize = 25
+
+const (
+	// sameArtistBoost and sameAlbumBoost nudge same-artist/same-album
+	// tracks up the ranking beyond what raw feature similarity gives them.
+	sameArtistBoost = 0.15
+	sameAlbumBoost  = 0.25
+
+	// recencyPenaltyMax and recencyPenaltyWindow discourage recommending
+	// tracks that played very recently, so the radio doesn't just loop
+	// the last few songs played. The penalty decays linearly from
+	// recencyPenaltyMax at "just played" to 0 at recencyPenaltyWindow.
+	recencyPenaltyMax    = 0.3
+	recencyPenaltyWindow = 24 * time.Hour
+)
+
+// radioCandidate pairs a track with its computed recommendation score so
+// the pool can be sorted before the scores are discarded.
+type radioCandidate struct {
+	track Track
+	score float64
+}
+
+// recommendTracks ranks pool by similarity to seed using cosine similarity
+// over z-normalized energy/loudness/duration, boosted for same-artist and
+// same-album matches and penalized for tracks played recently. Returns up
+// to radioPlaylistSize tracks in descending score order, seed excluded.
+func recommendTracks(pool []Track, seed Track) []Track {
+	energyMean, energyStd := meanStd(trackEnergies(pool))
+	loudnessMean, loudnessStd := meanStd(trackLoudness(pool))
+	durationMean, durationStd := meanStd(trackDurations(pool))
+
+	seedVec := []float64{
+		zScore(float64(seed.EnergyLevel), energyMean, energyStd),
+		zScore(seed.LoudnessLUFS, loudnessMean, loudnessStd),
+		zScore(seed.DurationSec, durationMean, durationStd),
+	}
+
+	var candidates []radioCandidate
+	for _, t := range pool {
+		if t.ID == seed.ID {
+			continue
+		}
+
+		vec := []float64{
+			zScore(float64(t.EnergyLevel), energyMean, energyStd),
+			zScore(t.LoudnessLUFS, loudnessMean, loudnessStd),
+			zScore(t.DurationSec, durationMean, durationStd),
+		}
+
+		score := cosineSimilarity(seedVec, vec)
+		if seed.Artist != "Unknown" && t.Artist == seed.Artist {
+			score += sameArtistBoost
+			if seed.Album != "Unknown" && t.Album == seed.Album {
+				score += sameAlbumBoost
+			}
+		}
+		score -= recencyPenalty(t.LastPlayedAt)
+
+		candidates = append(candidates, radioCandidate{track: t, score: score})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	n := radioPlaylistSize
+	if len(candidates) < n {
+		n = len(candidates)
+	}
+
+	tracks := make([]Track, n)
+	for i := 0; i < n; i++ {
+		tracks[i] = candidates[i].track
+	}
+	return tracks
+}
+
+func trackEnergies(tracks []Track) []float64 {
+	out := make([]float64, len(tracks))
+	for i, t := range tracks {
+		out[i] = float64(t.EnergyLevel)
+	}
+	return out
+}
+
+func trackLoudness(tracks []Track) []float64 {
+	out := make([]float64, len(tracks))
+	for i, t := range tracks {
+		out[i] = t.LoudnessLUFS
+	}
+	return out
+}
+
+func trackDurations(tracks []Track) []float64 {
+	out := make([]float64, len(tracks))
+	for i, t := range tracks {
+		out[i] = t.DurationSec
+	}
+	return out
+}
+
+func meanStd(values []float64) (mean, std float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean = sum / float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		d := v - mean
+		variance += d * d
+	}
+	variance /= float64(len(values))
+
+	return mean, math.Sqrt(variance)
+}
+
+// zScore returns 0 for a zero-variance feature instead of dividing by
+// zero, so a dataset where every track shares one energy level doesn't
+// blow up the similarity calculation.
+func zScore(v, mean, std float64) float64 {
+	if std == 0 {
+		return 0
+	}
+	return (v - mean) / std
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	var dot, magA, magB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		magA += a[i] * a[i]
+		magB += b[i] * b[i]
+	}
+	if magA == 0 || magB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(magA) * math.Sqrt(magB))
+}
+
+// recencyPenalty decays linearly from recencyPenaltyMax at "just played"
+// to 0 at recencyPenaltyWindow and beyond. Tracks with no play history
+// pay no penalty.
+func recencyPenalty(lastPlayedAt string) float64 {
+	if lastPlayedAt == "" {
+		return 0
+	}
+
+	t, err := time.Parse(time.RFC3339, lastPlayedAt)
+	if err != nil {
+		return 0
+	}
+
+	age := time.Since(t)
+	if age >= recencyPenaltyWindow {
+		return 0
+	}
+
+	fraction := 1 - float64(age)/float64(recencyPenaltyWindow)
+	return recencyPenaltyMax * fraction
+}
+
+// exportM3U writes tracks as an extended M3U playlist to path.
+func exportM3U(tracks []Track, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, "#EXTM3U")
+	for _, t := range tracks {
+		fmt.Fprintf(f, "#EXTINF:%d,%s - %s\n", int(t.DurationSec), t.Artist, t.Title)
+		fmt.Fprintln(f, t.Path)
+	}
+
+	return nil
+}
+
+// radioExportPath names an M3U export after the seed track and the time
+// it was generated, so repeated exports for different seeds don't collide.
+func radioExportPath(seed Track) string {
+	return fmt.Sprintf("radio-%s-%s.m3u", sanitizeFilename(seed.Title), time.Now().Format("20060102-150405"))
+}
+
+func sanitizeFilename(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			out = append(out, r)
+		case r == ' ' || r == '-' || r == '_':
+			out = append(out, '-')
+		}
+	}
+	if len(out) == 0 {
+		return "track"
+	}
+	return string(out)
+}
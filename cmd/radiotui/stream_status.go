@@ -0,0 +1,136 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// streamStatusPollInterval controls how often the TUI samples the
+// Icecast/Shoutcast status-json.xsl endpoint while a --stream-status-url
+// is configured.
+const streamStatusPollInterval = 15 * time.Second
+
+// icecastStatus mirrors the subset of status-json.xsl we care about.
+// Icecast reports "source" as an object when there's exactly one mount
+// and as an array when there are several, so Source is decoded twice.
+type icecastStatus struct {
+	Icestats struct {
+		Source json.RawMessage `json:"source"`
+	} `json:"icestats"`
+}
+
+type icecastSource struct {
+	Listeners    int `json:"listeners"`
+	ListenerPeak int `json:"listener_peak"`
+}
+
+// streamStatusTickMsg fires on a timer to trigger the next poll.
+type streamStatusTickMsg struct{}
+
+// streamStatusMsg carries the result of a single poll back into Update.
+type streamStatusMsg struct {
+	listeners int
+	err       error
+}
+
+// tickStreamStatusCmd schedules the next poll after streamStatusPollInterval.
+func tickStreamStatusCmd() tea.Cmd {
+	return tea.Tick(streamStatusPollInterval, func(time.Time) tea.Msg {
+		return streamStatusTickMsg{}
+	})
+}
+
+// pollStreamStatusCmd fetches the configured status-json.xsl endpoint and
+// reports the current listener count (summed across mounts/sources).
+func pollStreamStatusCmd(url string) tea.Cmd {
+	return func() tea.Msg {
+		listeners, err := fetchStreamListeners(url)
+		return streamStatusMsg{listeners: listeners, err: err}
+	}
+}
+
+var streamStatusHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+// fetchStreamListeners requests status-json.xsl and returns the total
+// listener count across all sources reported.
+func fetchStreamListeners(url string) (int, error) {
+	resp, err := streamStatusHTTPClient.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var status icecastStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return 0, err
+	}
+
+	// Try a single source object first, then fall back to an array.
+	var single icecastSource
+	if err := json.Unmarshal(status.Icestats.Source, &single); err == nil {
+		return single.Listeners, nil
+	}
+
+	var multiple []icecastSource
+	if err := json.Unmarshal(status.Icestats.Source, &multiple); err == nil {
+		total := 0
+		for _, src := range multiple {
+			total += src.Listeners
+		}
+		return total, nil
+	}
+
+	return 0, nil
+}
+
+// ensureStreamStatusSchema adds the listener-tracking columns to
+// play_history if this is the first run against a database that
+// predates listener tracking.
+func ensureStreamStatusSchema(db *sql.DB) error {
+	existing := map[string]bool{}
+	rows, err := db.Query(`PRAGMA table_info(play_history)`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var cid int
+		var name, colType string
+		var notNull, pk int
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	if !existing["listeners"] {
+		if _, err := db.Exec(`ALTER TABLE play_history ADD COLUMN listeners INTEGER`); err != nil {
+			return err
+		}
+	}
+	if !existing["peak_listeners"] {
+		if _, err := db.Exec(`ALTER TABLE play_history ADD COLUMN peak_listeners INTEGER`); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordStreamListeners samples listeners against the most recently
+// played row, bumping peak_listeners if the sample is a new high for
+// that play.
+func recordStreamListeners(db *sql.DB, listeners int) {
+	db.Exec(`
+		UPDATE play_history
+		SET listeners = ?,
+			peak_listeners = MAX(COALESCE(peak_listeners, 0), ?)
+		WHERE id = (SELECT id FROM play_history ORDER BY played_at DESC LIMIT 1)
+	`, listeners, listeners)
+}